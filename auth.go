@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// accessKey is the token required on every request when --key is set; empty
+// means access control is disabled.
+var accessKey string
+
+// generateAccessKey returns a random hex token suitable for --key, used
+// when the flag is given as "auto" to opt in without choosing one.
+func generateAccessKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate access key: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// accessKeyCookie is the cookie requireAccessKey sets once a request proves
+// it knows accessKey via ?key=, so the player page's sub-resource requests
+// (/video, /hls/*, /subtitles/*, ...) don't all need the query param
+// embedded in every URL the page emits.
+const accessKeyCookie = "vphs_key"
+
+// requireAccessKey wraps a handler so it 403s unless the request carries a
+// matching "key" query parameter or accessKeyCookie. It's a no-op when
+// accessKey is unset.
+func requireAccessKey(next http.HandlerFunc) http.HandlerFunc {
+	if accessKey == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		provided := r.URL.Query().Get("key")
+		if provided == "" {
+			if cookie, err := r.Cookie(accessKeyCookie); err == nil {
+				provided = cookie.Value
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(accessKey)) != 1 {
+			http.Error(w, "Forbidden: missing or invalid access key", http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: accessKeyCookie, Value: accessKey, Path: "/"})
+		next(w, r)
+	}
+}
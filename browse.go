@@ -0,0 +1,227 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// thumbnailCacheDir holds the per-file JPEG thumbnails generated on demand
+// for the directory browse page.
+var thumbnailCacheDir string
+
+// videoExtensions are the file extensions scanned for when videoFilePath
+// points at a directory instead of a single file.
+var videoExtensions = map[string]bool{
+	".mp4":  true,
+	".mkv":  true,
+	".webm": true,
+	".mov":  true,
+	".avi":  true,
+	".m4v":  true,
+}
+
+// mediaFile is one entry in a directory listing: its id (used in
+// /play/{id}, /video/{id} and /thumb/{id}), its file name, and its path on
+// disk (the directory as given on the command line joined with the name).
+type mediaFile struct {
+	ID   string
+	Name string
+	Path string
+}
+
+// mediaFiles holds the directory's scanned entries when videoFilePath is a
+// directory; empty when serving a single file.
+var mediaFiles []mediaFile
+
+// isDirectorySource reports whether the configured source is a directory to
+// browse rather than a single video file.
+func isDirectorySource() bool {
+	return len(mediaFiles) > 0
+}
+
+// scanDirectory walks dir (non-recursively) collecting video files into
+// mediaFiles, assigning each a stable numeric id based on sorted order.
+func scanDirectory(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if videoExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	mediaFiles = mediaFiles[:0]
+	for i, name := range names {
+		mediaFiles = append(mediaFiles, mediaFile{
+			ID:   fmt.Sprintf("%d", i),
+			Name: name,
+			Path: filepath.Join(dir, name),
+		})
+	}
+
+	if len(mediaFiles) == 0 {
+		return fmt.Errorf("no video files found in directory %s", dir)
+	}
+	return nil
+}
+
+// findMediaFile looks up a scanned file by its /video/{id} id.
+func findMediaFile(id string) (mediaFile, bool) {
+	for _, f := range mediaFiles {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return mediaFile{}, false
+}
+
+// browseHandler serves an index page listing the directory's video files,
+// each linking to its own player page.
+func browseHandler(w http.ResponseWriter, r *http.Request) {
+	var items strings.Builder
+	for _, f := range mediaFiles {
+		items.WriteString(fmt.Sprintf(
+			`<li><a href="/play/%s"><img class="thumb" src="/thumb/%s" alt=""> %s</a></li>`+"\n",
+			f.ID, f.ID, htmlEscape(f.Name),
+		))
+	}
+
+	html := fmt.Sprintf(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>%s - Media Browser</title>
+    <style>
+        body { font-family: Arial, sans-serif; background-color: #f0f0f0; padding: 20px; }
+        h1 { color: #333; }
+        ul { list-style: none; padding: 0; }
+        li { background: #fff; margin: 8px 0; padding: 12px 16px; border-radius: 6px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+        a { text-decoration: none; color: #1a73e8; font-size: 1.1em; display: flex; align-items: center; gap: 12px; }
+        .thumb { width: 120px; height: 68px; object-fit: cover; border-radius: 4px; background: #ddd; }
+    </style>
+</head>
+<body>
+    <h1>%s</h1>
+    <p><a href="/download.zip">Download all as .zip</a></p>
+    <ul>
+%s    </ul>
+</body>
+</html>
+`, filepath.Base(videoFilePath), filepath.Base(videoFilePath), items.String())
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(html))
+}
+
+// htmlEscape escapes the handful of characters that matter in the simple
+// list markup above.
+func htmlEscape(s string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return replacer.Replace(s)
+}
+
+// playHandler serves the player page for a single file within a directory
+// source, identified by its /play/{id} id.
+func playHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/play/")
+	f, ok := findMediaFile(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	renderPlayer(w, f.Name, fmt.Sprintf("/video/%s", f.ID))
+}
+
+// videoByIDHandler serves a single file from the directory with Range
+// support, analogous to videoHandler but routed by id.
+func videoByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/video/")
+	f, ok := findMediaFile(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	serveVideoFile(w, r, f.Path)
+}
+
+// thumbnailHandler serves (generating and caching on first request) a JPEG
+// thumbnail for a directory entry, grabbed from a few seconds into the clip
+// with ffmpeg so the browse page isn't just a bare file list.
+func thumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/thumb/")
+	f, ok := findMediaFile(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	thumbPath := filepath.Join(thumbnailCacheDir, f.ID+".jpg")
+	if _, err := os.Stat(thumbPath); os.IsNotExist(err) {
+		cmd := exec.Command("ffmpeg", "-y", "-ss", "3", "-i", f.Path, "-frames:v", "1", "-vf", "scale=320:-1", thumbPath)
+		if err := cmd.Run(); err != nil {
+			http.Error(w, "failed to generate thumbnail: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.ServeFile(w, r, thumbPath)
+}
+
+// downloadZipHandler streams a zip of every scanned file using archive/zip
+// over an io.Pipe, so nothing is buffered to a temp file.
+func downloadZipHandler(w http.ResponseWriter, r *http.Request) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		zw := zip.NewWriter(pw)
+		for _, f := range mediaFiles {
+			if err := addFileToZip(zw, f); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		if err := zw.Close(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.Close()
+	}()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, url.PathEscape(filepath.Base(videoFilePath))))
+	io.Copy(w, pr)
+}
+
+// addFileToZip copies one media file's contents into the zip stream.
+func addFileToZip(zw *zip.Writer, f mediaFile) error {
+	src, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Path, err)
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(f.Name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to zip: %w", f.Name, err)
+	}
+
+	_, err = io.Copy(dst, src)
+	return err
+}
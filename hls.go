@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// hlsRendition describes a single adaptive-bitrate output produced by ffmpeg.
+type hlsRendition struct {
+	Name      string // rendition directory name, also used as the playlist NAME attribute
+	Scale     string // ffmpeg -vf scale value, e.g. "854:480"
+	VideoRate string // -b:v value, e.g. "1400k"
+	AudioRate string // -b:a value, e.g. "128k"
+}
+
+// hlsRenditions are the adaptive bitrate levels exposed in the master playlist,
+// ordered from lowest to highest so weaker LAN links can fall back gracefully.
+var hlsRenditions = []hlsRendition{
+	{Name: "480p", Scale: "854:480", VideoRate: "1400k", AudioRate: "128k"},
+	{Name: "720p", Scale: "1280:720", VideoRate: "2800k", AudioRate: "128k"},
+	{Name: "1080p", Scale: "1920:1080", VideoRate: "5000k", AudioRate: "192k"},
+}
+
+// Global variables for HLS mode, mirroring how videoFilePath/serverPort are
+// stored as package-level state for the single video being served.
+var (
+	hlsEnabled bool
+	hlsTempDir string
+	hlsCmd     *exec.Cmd
+)
+
+// isDirectlyPlayableMP4 reports whether the source file can be handed straight
+// to an HTML5 <video> tag without transcoding.
+func isDirectlyPlayableMP4(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp4", ".m4v":
+		return true
+	default:
+		return false
+	}
+}
+
+// startHLSTranscode launches ffmpeg to segment videoFilePath into a master
+// playlist plus one rendition directory per entry in hlsRenditions, writing
+// everything under outDir. ffmpeg keeps running in the background, appending
+// new segments as it encodes, so playback can start before encoding finishes.
+func startHLSTranscode(src, outDir string) (*exec.Cmd, error) {
+	args := []string{"-y", "-i", src}
+
+	var varStreamMap []string
+	for i, r := range hlsRenditions {
+		renditionDir := filepath.Join(outDir, r.Name)
+		if err := os.MkdirAll(renditionDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create rendition directory %s: %w", renditionDir, err)
+		}
+
+		args = append(args,
+			"-map", "0:v:0", "-map", "0:a:0",
+			fmt.Sprintf("-filter:v:%d", i), "scale="+r.Scale,
+			fmt.Sprintf("-b:v:%d", i), r.VideoRate,
+			fmt.Sprintf("-b:a:%d", i), r.AudioRate,
+		)
+		varStreamMap = append(varStreamMap, fmt.Sprintf("v:%d,a:%d,name:%s", i, i, r.Name))
+	}
+
+	args = append(args,
+		"-c:v", "libx264", "-c:a", "aac",
+		"-f", "hls",
+		"-hls_time", "4",
+		// "event" + append_list means the playlist is appended to as each
+		// segment finishes rather than written once encoding completes, so
+		// playback can start against the partial playlist/segment set.
+		"-hls_playlist_type", "event",
+		"-hls_flags", "independent_segments+append_list",
+		"-master_pl_name", "master.m3u8",
+		"-var_stream_map", strings.Join(varStreamMap, " "),
+		"-hls_segment_filename", filepath.Join(outDir, "%v", "segment_%03d.ts"),
+		filepath.Join(outDir, "%v", "playlist.m3u8"),
+	)
+
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	return cmd, nil
+}
+
+// hlsHandler serves the generated master playlist, per-rendition playlists
+// and .ts segments out of hlsTempDir, with the same path layout ffmpeg wrote
+// them in (e.g. /hls/720p/segment_000.ts).
+func hlsHandler(w http.ResponseWriter, r *http.Request) {
+	if hlsTempDir == "" {
+		http.Error(w, "HLS mode is not enabled", http.StatusNotFound)
+		return
+	}
+	http.StripPrefix("/hls/", http.FileServer(http.Dir(hlsTempDir))).ServeHTTP(w, r)
+}
+
+// cleanupHLS stops the background ffmpeg process (if any) and removes the
+// temporary directory holding the generated playlists/segments.
+func cleanupHLS() {
+	if hlsCmd != nil && hlsCmd.Process != nil {
+		hlsCmd.Process.Kill()
+	}
+	if hlsTempDir != "" {
+		os.RemoveAll(hlsTempDir)
+	}
+}
@@ -10,9 +10,14 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+
+	syncpkg "github.com/cjacker/vphs/sync"
+	rtcbridge "github.com/cjacker/vphs/webrtc"
 )
 
 // Use ascii blocks to form the QR Code
@@ -25,8 +30,24 @@ const WHITE_WHITE = "█"
 var (
 	videoFilePath string
 	serverPort    int // Changed to int type for easier parameter parsing
+	stunServers   string
+	rtspBridge    *rtcbridge.Bridge
+
+	useTLS   bool
+	certFile string
+	keyFile  string
+
+	embeddedTracks []mediaTrack
+
+	syncEnabled bool
+	syncHub     *syncpkg.Hub
 )
 
+// isRTSPSource reports whether path is an rtsp:// URL rather than a local file.
+func isRTSPSource(path string) bool {
+	return strings.HasPrefix(path, "rtsp://")
+}
+
 // Print help information
 func printHelp() {
 	helpText := `
@@ -40,14 +61,33 @@ Features:
 
 Options:
   -p, --port int   Specify service port (default 9090, range 1-65535)
+      --hls        Transcode the source into an adaptive bitrate HLS stream
+                    (480p/720p/1080p) instead of serving it directly; use this
+                    for sources a browser can't play natively (e.g. MKV/HEVC)
+      --stun       Comma-separated STUN server URLs for WebRTC playback of
+                    rtsp:// sources (default stun:stun.l.google.com:19302)
+      --key string Require this access token on every request (as ?key=...);
+                    pass "auto" to generate a random one and print it at startup
+      --tls        Serve over HTTPS; generates a self-signed certificate
+                    unless --cert/--key-file are given
+      --cert       TLS certificate file (used with --tls and --key-file)
+      --key-file   TLS private key file (used with --tls and --cert)
+      --sync       Enable "watch party" mode: keep every viewer's playback
+                    in sync over WebSocket, with a shared chat pane
 
 Examples:
   video-player ./movie.mp4                # Use default port 9090
   video-player -p 8888 ./movie.mp4        # Use port 8888
   video-player --port 7070 /home/video.mp4
+  video-player rtsp://192.168.1.50:554/stream   # Low-latency WebRTC playback
 
 Parameters:
-  video file path    Absolute/relative path of the video file to play
+  video file path    Absolute/relative path of the video file to play, an
+                      rtsp:// URL (e.g. an IP camera stream) for WebRTC
+                      playback (a local camera device path is not streamed
+                      over WebRTC; it is handled like a file), or a
+                      directory to browse (lists its video files, with a
+                      /download.zip to fetch them all at once)
 
 Access Methods:
   1. Local access: http://localhost:port
@@ -122,10 +162,40 @@ func getLocalIPForGateway(gwIP net.IP) (net.IP, error) {
 	return nil, fmt.Errorf("no local IPv4 address found in the same subnet as gateway %s", gwIP.String())
 }
 
-// Handle HTTP requests for video files (supports Range partial content)
+// Handle HTTP requests for the configured video file (supports Range partial content)
 func videoHandler(w http.ResponseWriter, r *http.Request) {
+	if audio, sub, ok := parseTrackSelection(r); ok {
+		remuxSelectedTracks(w, r, videoFilePath, audio, sub)
+		return
+	}
+	serveVideoFile(w, r, videoFilePath)
+}
+
+// parseTrackSelection reads ?audio=N&sub=M off the request; ok is false
+// when neither was given, so callers fall back to the plain Range path.
+func parseTrackSelection(r *http.Request) (audio, sub int, ok bool) {
+	q := r.URL.Query()
+	audioStr, subStr := q.Get("audio"), q.Get("sub")
+	if audioStr == "" && subStr == "" {
+		return 0, 0, false
+	}
+
+	audio, sub = -1, -1
+	if audioStr != "" {
+		fmt.Sscanf(audioStr, "%d", &audio)
+	}
+	if subStr != "" {
+		fmt.Sscanf(subStr, "%d", &sub)
+	}
+	return audio, sub, true
+}
+
+// serveVideoFile streams path to w, honoring a Range header for partial
+// content the same way videoHandler always has; videoByIDHandler reuses
+// this to serve one file out of a directory source.
+func serveVideoFile(w http.ResponseWriter, r *http.Request, path string) {
 	// Open video file
-	file, err := os.Open(videoFilePath)
+	file, err := os.Open(path)
 	if err != nil {
 		http.Error(w, "Failed to open video file: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -189,10 +259,88 @@ func videoHandler(w http.ResponseWriter, r *http.Request) {
 
 // Provide HTML page with embedded video player
 func playerHandler(w http.ResponseWriter, r *http.Request) {
-	// Get video file name (for page title)
-	filename := filepath.Base(videoFilePath)
+	// A directory source has no single file to play at "/" — show the
+	// browsable index instead.
+	if isDirectorySource() {
+		browseHandler(w, r)
+		return
+	}
+	renderPlayer(w, filepath.Base(videoFilePath), "/video")
+}
+
+// renderPlayer writes the player page for filename, sourcing the video from
+// videoSrc. HLS/RTSP modes only apply to the single configured source, so
+// directory-mode files go through the plain HTML5 <video> branch whatever
+// their container (MKV/WebM/AVI/MOV included).
+func renderPlayer(w http.ResponseWriter, filename, videoSrc string) {
+	// HLS mode (or any source a browser can't play natively) gets an
+	// hls.js-backed player pointed at the generated master playlist;
+	// everything else keeps the plain HTML5 <video> + byte-range source.
+	var playerBlock string
+	if isRTSPSource(videoFilePath) {
+		playerBlock = `
+    <video id="player" autoplay muted playsinline controls></video>
+    <script>
+        var video = document.getElementById('player');
+        var pc = new RTCPeerConnection();
+        pc.ontrack = function(event) { video.srcObject = event.streams[0]; };
+        var pendingCandidates = [];
+        function sendCandidate(candidate) {
+            fetch('/webrtc/candidate', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({sessionId: window._vphsSessionId, candidate: candidate})
+            });
+        }
+        pc.onicecandidate = function(event) {
+            if (!event.candidate) return;
+            if (window._vphsSessionId) sendCandidate(event.candidate);
+            else pendingCandidates.push(event.candidate);
+        };
+        pc.addTransceiver('video', {direction: 'recvonly'});
+        pc.addTransceiver('audio', {direction: 'recvonly'});
+        pc.createOffer().then(function(offer) {
+            return pc.setLocalDescription(offer).then(function() { return offer; });
+        }).then(function(offer) {
+            return fetch('/webrtc/offer', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({sdp: offer.sdp})
+            });
+        }).then(function(resp) { return resp.json(); }).then(function(answer) {
+            window._vphsSessionId = answer.sessionId;
+            pendingCandidates.forEach(sendCandidate);
+            pendingCandidates = [];
+            return pc.setRemoteDescription({type: 'answer', sdp: answer.sdp});
+        });
+    </script>
+`
+	} else if hlsEnabled {
+		playerBlock = fmt.Sprintf(`
+    <video id="player" controls autoplay preload="metadata"></video>
+    <script src="https://cdn.jsdelivr.net/npm/hls.js@1/dist/hls.min.js"></script>
+    <script>
+        var video = document.getElementById('player');
+        var src = '/hls/master.m3u8';
+        if (video.canPlayType('application/vnd.apple.mpegurl')) {
+            video.src = src;
+        } else if (Hls.isSupported()) {
+            var hls = new Hls();
+            hls.loadSource(src);
+            hls.attachMedia(video);
+        }
+    </script>
+`)
+	} else {
+		playerBlock = fmt.Sprintf(`
+    <video controls autoplay preload="metadata">
+        <source src="%s" type="video/mp4">
+%s        Your browser does not support HTML5 video playback. Please upgrade your browser.
+    </video>
+%s`, videoSrc, subtitleTrackElements(), trackSelectorBlock())
+	}
 
-	// HTML page with embedded HTML5 video player
+	// HTML page with embedded video player
 	html := fmt.Sprintf(`
 <!DOCTYPE html>
 <html lang="en">
@@ -220,17 +368,28 @@ func playerHandler(w http.ResponseWriter, r *http.Request) {
             border-radius: 8px;
             box-shadow: 0 4px 8px rgba(0,0,0,0.2);
         }
+        #chat {
+            width: 90%%;
+            max-width: 1200px;
+            margin-top: 12px;
+        }
+        #chatLog {
+            height: 120px;
+            overflow-y: auto;
+            background: #fff;
+            border-radius: 8px;
+            padding: 8px;
+            font-size: 0.9em;
+        }
     </style>
 </head>
 <body>
     <h1>%s</h1>
-    <video controls autoplay preload="metadata">
-        <source src="/video" type="video/mp4">
-        Your browser does not support HTML5 video playback. Please upgrade your browser.
-    </video>
+    %s
+    %s
 </body>
 </html>
-`, filename, filename)
+`, filename, filename, playerBlock, watchPartyBlock())
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
@@ -240,9 +399,25 @@ func main() {
 	// Define port parameter, default 9090
 	flag.IntVar(&serverPort, "port", 9090, "Specify service port (default 9090)")
 	flag.IntVar(&serverPort, "p", 9090, "Specify service port (short)")
+	flag.BoolVar(&hlsEnabled, "hls", false, "Transcode the source into an adaptive bitrate HLS stream")
+	flag.StringVar(&stunServers, "stun", "stun:stun.l.google.com:19302", "Comma-separated STUN server URLs used for WebRTC (rtsp:// sources)")
+	flag.StringVar(&accessKey, "key", "", `Require this access token on every request; pass "auto" to generate one`)
+	flag.BoolVar(&useTLS, "tls", false, "Serve over HTTPS (self-signed unless --cert/--key-file are given)")
+	flag.StringVar(&certFile, "cert", "", "TLS certificate file")
+	flag.StringVar(&keyFile, "key-file", "", "TLS private key file")
+	flag.BoolVar(&syncEnabled, "sync", false, `Enable synchronized multi-viewer "watch party" mode`)
 	flag.Usage = printHelp
 	flag.Parse()
 
+	if accessKey == "auto" {
+		generated, err := generateAccessKey()
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		accessKey = generated
+	}
+
 	// Validate port validity (1-65535)
 	if serverPort < 1 || serverPort > 65535 {
 		fmt.Printf("Error: Port number %d is invalid, must be in the range 1-65535\n", serverPort)
@@ -256,16 +431,112 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Verify video file exists
+	// Verify video file exists (skipped for rtsp:// sources, which aren't
+	// local files and are validated by connecting to them instead)
 	videoFilePath = args[0]
-	if _, err := os.Stat(videoFilePath); os.IsNotExist(err) {
-		fmt.Printf("Error: Video file does not exist -> %s\n", videoFilePath)
-		os.Exit(1)
+	if !isRTSPSource(videoFilePath) {
+		info, err := os.Stat(videoFilePath)
+		if os.IsNotExist(err) {
+			fmt.Printf("Error: Video file does not exist -> %s\n", videoFilePath)
+			os.Exit(1)
+		}
+		if info.IsDir() {
+			if err := scanDirectory(videoFilePath); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+
+			tempDir, err := os.MkdirTemp("", "vphs-thumbs-")
+			if err != nil {
+				fmt.Printf("Error: failed to create thumbnail cache dir: %s\n", err.Error())
+				os.Exit(1)
+			}
+			thumbnailCacheDir = tempDir
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+			go func() {
+				<-sigCh
+				os.RemoveAll(thumbnailCacheDir)
+				os.Exit(0)
+			}()
+			// Probe for embedded audio/subtitle tracks (e.g. an MKV with
+			// multiple language tracks) so the player can offer ?audio=N&sub=M;
+			// only the plain <video> player shows the selectors, so a source
+			// that ends up in HLS mode is probed but offers none.
+		} else if tracks, err := ffprobeTracks(videoFilePath); err == nil {
+			embeddedTracks = tracks
+		}
+
+		// Auto-enable HLS for sources a browser can't play natively (e.g.
+		// MKV/HEVC), rather than requiring the user to know to pass --hls;
+		// directory sources are handled per-file instead, with no single
+		// source to transcode up front, so --hls is ignored for them.
+		if info.IsDir() {
+			hlsEnabled = false
+		} else if !isDirectlyPlayableMP4(videoFilePath) {
+			hlsEnabled = true
+		}
+	}
+
+	// In HLS mode, spin up ffmpeg to segment the source into a temp dir and
+	// clean it up on shutdown instead of serving the raw file directly.
+	if hlsEnabled {
+		tempDir, err := os.MkdirTemp("", "vphs-hls-")
+		if err != nil {
+			fmt.Printf("Error: failed to create HLS temp dir: %s\n", err.Error())
+			os.Exit(1)
+		}
+		hlsTempDir = tempDir
+
+		cmd, err := startHLSTranscode(videoFilePath, hlsTempDir)
+		if err != nil {
+			fmt.Printf("Error: failed to start HLS transcode: %s\n", err.Error())
+			os.Exit(1)
+		}
+		hlsCmd = cmd
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			cleanupHLS()
+			os.Exit(0)
+		}()
 	}
 
-	// Register HTTP routes
-	http.HandleFunc("/", playerHandler)
-	http.HandleFunc("/video", videoHandler)
+	// rtsp:// sources are streamed over WebRTC instead of served as a file.
+	if isRTSPSource(videoFilePath) {
+		rtspBridge = rtcbridge.New(videoFilePath, strings.Split(stunServers, ","))
+		rtcMux := http.NewServeMux()
+		rtspBridge.RegisterHandlers(rtcMux)
+		http.HandleFunc("/webrtc/", requireAccessKey(rtcMux.ServeHTTP))
+		go func() {
+			if err := rtspBridge.Start(); err != nil {
+				fmt.Printf("\nWebRTC bridge failed: %s\n", err.Error())
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Register HTTP routes; every route is gated by requireAccessKey, which
+	// is a no-op when --key wasn't set.
+	http.HandleFunc("/", requireAccessKey(playerHandler))
+	http.HandleFunc("/video", requireAccessKey(videoHandler))
+	http.HandleFunc("/hls/", requireAccessKey(hlsHandler))
+	if isDirectorySource() {
+		http.HandleFunc("/play/", requireAccessKey(playHandler))
+		http.HandleFunc("/video/", requireAccessKey(videoByIDHandler))
+		http.HandleFunc("/thumb/", requireAccessKey(thumbnailHandler))
+		http.HandleFunc("/download.zip", requireAccessKey(downloadZipHandler))
+	}
+	if !isRTSPSource(videoFilePath) && !isDirectorySource() {
+		http.HandleFunc("/subtitles/", requireAccessKey(subtitleHandler))
+	}
+	if syncEnabled {
+		syncHub = syncpkg.NewHub()
+		http.HandleFunc("/ws", requireAccessKey(syncHub.Handler))
+	}
 
 	// Get local LAN IP
 	localIP, err := localIPString()
@@ -273,11 +544,24 @@ func main() {
 		fmt.Printf("Failed to get local IP address: %s\n", err.Error())
 		os.Exit(1)
 	}
-	accessURL := fmt.Sprintf("http://%s:%d", localIP, serverPort)
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	accessURL := fmt.Sprintf("%s://%s:%d", scheme, localIP, serverPort)
+	if accessKey != "" {
+		accessURL += "?key=" + accessKey
+	}
 
 	// Start HTTP service (asynchronous to avoid blocking QR code generation)
 	go func() {
-		err := http.ListenAndServe(fmt.Sprintf(":%d", serverPort), nil)
+		var err error
+		if useTLS {
+			err = serveTLS(fmt.Sprintf(":%d", serverPort), localIP)
+		} else {
+			err = http.ListenAndServe(fmt.Sprintf(":%d", serverPort), nil)
+		}
 		if err != nil {
 			fmt.Printf("\nService startup failed: %s\n", err.Error())
 			os.Exit(1)
@@ -287,8 +571,11 @@ func main() {
 	// Output startup information
 	fmt.Printf("======================\n")
 	fmt.Printf("Video file: %s\n", videoFilePath)
-	fmt.Printf("Local access: http://localhost:%d\n", serverPort)
+	fmt.Printf("Local access: %s://localhost:%d\n", scheme, serverPort)
 	fmt.Printf("LAN access: %s\n", accessURL)
+	if accessKey != "" {
+		fmt.Printf("Access key: %s\n", accessKey)
+	}
 	fmt.Println("======================")
 	fmt.Println("Scan QR code to access (mobile phone and computer must be on the same LAN):")
 
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// subtitleExtensions lists the sidecar subtitle extensions looked for next
+// to the video; all but .vtt are converted to WebVTT when served.
+var subtitleExtensions = []string{".srt", ".vtt", ".ass"}
+
+// subtitleTrack is one sidecar subtitle file discovered next to the video,
+// identified by the language tag taken from its filename (movie.en.srt ->
+// "en"), falling back to "und" when there's no tag.
+type subtitleTrack struct {
+	Lang string
+	Path string
+}
+
+// findSidecarSubtitles looks for movie.srt, movie.en.srt, movie.vtt, etc.
+// next to videoPath.
+func findSidecarSubtitles(videoPath string) []subtitleTrack {
+	dir := filepath.Dir(videoPath)
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var tracks []subtitleTrack
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), base) {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if !isSubtitleExtension(ext) {
+			continue
+		}
+
+		rest := strings.TrimSuffix(strings.TrimPrefix(e.Name(), base), ext)
+		lang := strings.Trim(rest, ".")
+		if lang == "" {
+			lang = "und"
+		}
+
+		tracks = append(tracks, subtitleTrack{Lang: lang, Path: filepath.Join(dir, e.Name())})
+	}
+	return tracks
+}
+
+func isSubtitleExtension(ext string) bool {
+	for _, e := range subtitleExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// subtitleHandler serves /subtitles/{lang}, converting SRT/ASS to WebVTT on
+// the fly so the <track> element can always be given "text/vtt".
+func subtitleHandler(w http.ResponseWriter, r *http.Request) {
+	lang := strings.TrimPrefix(r.URL.Path, "/subtitles/")
+
+	var track *subtitleTrack
+	for _, t := range findSidecarSubtitles(videoFilePath) {
+		if t.Lang == lang {
+			track = &t
+			break
+		}
+	}
+	if track == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+
+	if strings.ToLower(filepath.Ext(track.Path)) == ".vtt" {
+		http.ServeFile(w, r, track.Path)
+		return
+	}
+
+	vtt, err := convertToWebVTT(track.Path)
+	if err != nil {
+		http.Error(w, "failed to convert subtitles: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(vtt)
+}
+
+// convertToWebVTT shells out to ffmpeg to turn an SRT/ASS sidecar into
+// WebVTT; both formats are something ffmpeg's subtitle filters already
+// understand, so there's no need to hand-roll a parser here.
+func convertToWebVTT(path string) ([]byte, error) {
+	cmd := exec.Command("ffmpeg", "-y", "-i", path, "-f", "webvtt", "pipe:1")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg subtitle conversion failed: %w", err)
+	}
+	return out, nil
+}
+
+// mediaTrack describes one embedded audio or subtitle track reported by
+// ffprobe, used to populate the ?audio=N&sub=M selectors.
+type mediaTrack struct {
+	Index    int    `json:"index"`
+	Type     string `json:"codec_type"`
+	Codec    string `json:"codec_name"`
+	Language string `json:"-"`
+}
+
+// ffprobeTracks runs ffprobe once at startup to list the embedded
+// audio/subtitle tracks in a container like MKV.
+func ffprobeTracks(path string) ([]mediaTrack, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "stream=index,codec_type,codec_name",
+		"-select_streams", "a,s", "-of", "json", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var result struct {
+		Streams []mediaTrack `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	return result.Streams, nil
+}
+
+// canPassthroughAudio reports whether the browser can play the selected
+// audio codec directly (AAC/Opus/MP3), vs. needing the slow transcode path.
+func canPassthroughAudio(codec string) bool {
+	switch codec {
+	case "aac", "opus", "mp3":
+		return true
+	default:
+		return false
+	}
+}
+
+// trackByIndex looks up a probed track by its absolute container stream
+// index (the index ffprobe reports, used in ?audio=N&sub=M).
+func trackByIndex(index int) (mediaTrack, bool) {
+	for _, t := range embeddedTracks {
+		if t.Index == index {
+			return t, true
+		}
+	}
+	return mediaTrack{}, false
+}
+
+// subtitleOrdinal converts a track's absolute container stream index into
+// its 0-based position among subtitle streams only, which is what
+// ffmpeg's "subtitles" filter's si option expects.
+func subtitleOrdinal(index int) (int, bool) {
+	ordinal := 0
+	for _, t := range embeddedTracks {
+		if t.Type != "subtitle" {
+			continue
+		}
+		if t.Index == index {
+			return ordinal, true
+		}
+		ordinal++
+	}
+	return 0, false
+}
+
+// remuxSelectedTracks serves the video with only the chosen audio/subtitle
+// track muxed in. Switching audio alone is container-copied (no re-encode)
+// when the browser can already decode the chosen codec; burning in a
+// subtitle always needs ffmpeg to re-render the video frames.
+func remuxSelectedTracks(w http.ResponseWriter, r *http.Request, path string, audioIdx, subIdx int) {
+	args := []string{"-i", path, "-map", "0:v:0"}
+
+	fastPath := true
+	if audioIdx >= 0 {
+		args = append(args, "-map", fmt.Sprintf("0:%d", audioIdx))
+		if t, ok := trackByIndex(audioIdx); !ok || !canPassthroughAudio(t.Codec) {
+			fastPath = false
+		}
+	} else {
+		// "Default" audio keeps the container's first audio stream, if any.
+		args = append(args, "-map", "0:a:0?")
+		for _, t := range embeddedTracks {
+			if t.Type == "audio" {
+				fastPath = canPassthroughAudio(t.Codec)
+				break
+			}
+		}
+	}
+	if subIdx >= 0 {
+		// A selected subtitle is always burned into the video frame rather
+		// than muxed as a stream, which always requires re-encoding the
+		// video.
+		fastPath = false
+		ordinal, ok := subtitleOrdinal(subIdx)
+		if !ok {
+			ordinal = 0
+		}
+		args = append(args, "-vf", fmt.Sprintf("subtitles='%s':si=%d", escapeFFmpegPath(path), ordinal))
+	}
+
+	if fastPath {
+		args = append(args, "-c", "copy")
+	} else {
+		args = append(args, "-c:v", "libx264", "-c:a", "aac")
+	}
+	args = append(args, "-f", "mp4", "-movflags", "frag_keyframe+empty_moov", "pipe:1")
+
+	cmd := exec.Command("ffmpeg", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		http.Error(w, "failed to start transcode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	if err := cmd.Start(); err != nil {
+		http.Error(w, "failed to start ffmpeg: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer cmd.Wait()
+
+	writer := bufio.NewWriter(w)
+	writer.ReadFrom(stdout)
+	writer.Flush()
+}
+
+// escapeFFmpegPath escapes characters that are significant inside an
+// ffmpeg filtergraph argument.
+func escapeFFmpegPath(path string) string {
+	return strings.ReplaceAll(path, "'", `\'`)
+}
+
+// subtitleTrackElements renders one <track> per sidecar subtitle file found
+// next to videoFilePath, for embedding inside the <video> element.
+func subtitleTrackElements() string {
+	var b strings.Builder
+	for _, t := range findSidecarSubtitles(videoFilePath) {
+		fmt.Fprintf(&b, `        <track kind="subtitles" srclang="%s" label="%s" src="/subtitles/%s">`+"\n",
+			t.Lang, t.Lang, t.Lang)
+	}
+	return b.String()
+}
+
+// trackSelectorBlock renders a pair of <select> elements for choosing an
+// embedded audio/subtitle track when the source has more than one (as
+// probed with ffprobe at startup), reloading the video with the chosen
+// ?audio=N&sub=M. Only the plain <video> player emits it; sources played
+// over HLS (any non-MP4 file, e.g. MKV) or WebRTC get no selectors.
+func trackSelectorBlock() string {
+	if len(embeddedTracks) <= 1 {
+		return ""
+	}
+
+	var options strings.Builder
+	options.WriteString(`    <div class="track-select">` + "\n")
+	options.WriteString(`        <label>Audio: <select id="audioSelect"><option value="-1">Default</option>`)
+	for _, t := range embeddedTracks {
+		if t.Type == "audio" {
+			fmt.Fprintf(&options, `<option value="%d">%d: %s</option>`, t.Index, t.Index, t.Codec)
+		}
+	}
+	options.WriteString(`</select></label>` + "\n")
+
+	options.WriteString(`        <label>Subtitle: <select id="subSelect"><option value="-1">None</option>`)
+	for _, t := range embeddedTracks {
+		if t.Type == "subtitle" {
+			fmt.Fprintf(&options, `<option value="%d">%d: %s</option>`, t.Index, t.Index, t.Codec)
+		}
+	}
+	options.WriteString(`</select></label>` + "\n")
+	options.WriteString(`    </div>` + "\n")
+	options.WriteString(`    <script>
+        var audioSelect = document.getElementById('audioSelect');
+        var subSelect = document.getElementById('subSelect');
+        function reloadWithTracks() {
+            var video = document.querySelector('video');
+            video.src = '/video?audio=' + audioSelect.value + '&sub=' + subSelect.value;
+            video.play();
+        }
+        audioSelect.addEventListener('change', reloadWithTracks);
+        subSelect.addEventListener('change', reloadWithTracks);
+    </script>
+`)
+	return options.String()
+}
@@ -0,0 +1,35 @@
+package sync
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The player is only ever served to LAN viewers of the same share, so
+	// any origin is accepted here same as the rest of the tool's routes.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Handler upgrades the request to a websocket connection and joins it to
+// the watch party, to be registered at /ws.
+func (h *Hub) Handler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("sync: websocket upgrade failed: %v", err)
+		return
+	}
+
+	c, err := h.join(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	go c.writePump()
+	h.readPump(c)
+}
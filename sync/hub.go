@@ -0,0 +1,186 @@
+// Package sync implements the "watch party" hub: it keeps every connected
+// browser's player in lockstep by broadcasting play/pause/seek events, and
+// carries a small chat pane alongside it.
+package sync
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// message is the envelope for everything sent over the /ws connection,
+// both state deltas and chat lines.
+type message struct {
+	Type     string  `json:"type"`             // "state", "chat", "host"
+	Action   string  `json:"action,omitempty"` // "play", "pause", "seeked", "timeupdate"
+	Time     float64 `json:"time"`
+	Text     string  `json:"text,omitempty"`
+	ClientID string  `json:"clientId,omitempty"`
+	Target   string  `json:"target,omitempty"` // client id to hand host control to
+	IsHost   bool    `json:"isHost,omitempty"`
+}
+
+// client is one connected browser.
+type client struct {
+	id   string
+	conn *websocket.Conn
+	send chan message
+}
+
+// Hub tracks every connected client for a single watch party and rebroadcasts
+// state deltas between them. The first client to join is the authoritative
+// "host" whose play/pause/seek events drive everyone else, until control is
+// explicitly handed off.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[string]*client
+	hostID  string
+}
+
+// NewHub creates an empty watch-party hub.
+func NewHub() *Hub {
+	return &Hub{clients: make(map[string]*client)}
+}
+
+// newClientID generates a short random id to identify a client's connection
+// in broadcast and host-handoff messages; a reconnect gets a new id.
+func newClientID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// join registers conn as a new client, electing it host if it's the first
+// to connect, and returns the client so the caller can start its pumps.
+func (h *Hub) join(conn *websocket.Conn) (*client, error) {
+	id, err := newClientID()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{id: id, conn: conn, send: make(chan message, 16)}
+
+	h.mu.Lock()
+	isFirst := len(h.clients) == 0
+	h.clients[id] = c
+	if isFirst {
+		h.hostID = id
+	}
+	h.mu.Unlock()
+
+	c.send <- message{Type: "host", ClientID: id, IsHost: isFirst}
+	return c, nil
+}
+
+// leave removes a disconnected client, promoting an arbitrary remaining
+// client (map iteration order) to host if the host just left.
+func (h *Hub) leave(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, id)
+	if h.hostID != id {
+		return
+	}
+
+	h.hostID = ""
+	for otherID, c := range h.clients {
+		h.hostID = otherID
+		h.sendNonBlocking(c, message{Type: "host", ClientID: otherID, IsHost: true})
+		break
+	}
+}
+
+// sendNonBlocking delivers msg to c, dropping it instead of blocking when
+// c's buffer is full so h.mu is never held across a stalled send; callers
+// must hold h.mu.
+func (h *Hub) sendNonBlocking(c *client, msg message) {
+	select {
+	case c.send <- msg:
+	default:
+		log.Printf("sync: dropping message to slow client %s", c.id)
+	}
+}
+
+// broadcast fans msg out to every client except from.
+func (h *Hub) broadcast(msg message, from string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, c := range h.clients {
+		if id == from {
+			continue
+		}
+		h.sendNonBlocking(c, msg)
+	}
+}
+
+// isHost reports whether id is the currently authoritative host.
+func (h *Hub) isHost(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hostID == id
+}
+
+// handoffHost makes newHostID authoritative, provided requesterID is the
+// current host — anyone else's handoff request is ignored so a guest can't
+// self-promote and hijack playback control.
+func (h *Hub) handoffHost(requesterID, newHostID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hostID != requesterID {
+		return
+	}
+	if _, ok := h.clients[newHostID]; !ok {
+		return
+	}
+	h.hostID = newHostID
+	for id, c := range h.clients {
+		h.sendNonBlocking(c, message{Type: "host", ClientID: id, IsHost: id == newHostID})
+	}
+}
+
+// writePump drains c.send to the websocket connection.
+func (c *client) writePump() {
+	for msg := range c.send {
+		if err := c.conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+// readPump reads incoming state/chat messages from the browser and hands
+// them to the hub for broadcast, until the connection closes.
+func (h *Hub) readPump(c *client) {
+	defer func() {
+		h.leave(c.id)
+		close(c.send)
+		c.conn.Close()
+	}()
+
+	for {
+		var msg message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		if msg.Type == "host" {
+			h.handoffHost(c.id, msg.Target)
+			continue
+		}
+		// Only the current host's playback actions drive everyone else;
+		// a non-host client's state message is silently ignored rather
+		// than letting any guest force-control the shared player.
+		if msg.Type == "state" && !h.isHost(c.id) {
+			continue
+		}
+
+		msg.ClientID = c.id
+		h.broadcast(msg, c.id)
+	}
+}
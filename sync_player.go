@@ -0,0 +1,72 @@
+package main
+
+// watchPartyBlock renders the chat pane and the JS that hooks the <video>
+// element's timeupdate/play/pause/seeked events to /ws, keeping every
+// connected viewer's playback in lockstep. Returns "" when --sync wasn't passed.
+func watchPartyBlock() string {
+	if !syncEnabled {
+		return ""
+	}
+
+	return `
+    <div id="chat">
+        <div id="chatLog"></div>
+        <input id="chatInput" type="text" placeholder="Say something…" style="width:100%">
+    </div>
+    <script>
+        (function() {
+            var video = document.querySelector('video');
+            var chatLog = document.getElementById('chatLog');
+            var chatInput = document.getElementById('chatInput');
+            var isHost = false;
+            var applyingRemote = false;
+            var proto = location.protocol === 'https:' ? 'wss://' : 'ws://';
+            var ws = new WebSocket(proto + location.host + '/ws' + location.search);
+
+            function send(msg) { ws.send(JSON.stringify(msg)); }
+
+            function appendChatLine(who, text) {
+                var line = document.createElement('div');
+                line.textContent = who + ': ' + text;
+                chatLog.appendChild(line);
+                chatLog.scrollTop = chatLog.scrollHeight;
+            }
+
+            ws.onmessage = function(event) {
+                var msg = JSON.parse(event.data);
+                if (msg.type === 'host') {
+                    isHost = msg.isHost;
+                    return;
+                }
+                if (msg.type === 'chat') {
+                    appendChatLine(msg.clientId, msg.text);
+                    return;
+                }
+                if (msg.type === 'state') {
+                    applyingRemote = true;
+                    if (Math.abs(video.currentTime - msg.time) > 0.5) {
+                        video.currentTime = msg.time;
+                    }
+                    if (msg.action === 'play') video.play();
+                    if (msg.action === 'pause') video.pause();
+                    applyingRemote = false;
+                }
+            };
+
+            ['play', 'pause', 'seeked', 'timeupdate'].forEach(function(evt) {
+                video.addEventListener(evt, function() {
+                    if (applyingRemote || !isHost) return;
+                    send({type: 'state', action: evt === 'seeked' ? 'seeked' : evt, time: video.currentTime});
+                });
+            });
+
+            chatInput.addEventListener('keydown', function(event) {
+                if (event.key !== 'Enter' || !chatInput.value) return;
+                send({type: 'chat', text: chatInput.value});
+                appendChatLine('you', chatInput.value);
+                chatInput.value = '';
+            });
+        })();
+    </script>
+`
+}
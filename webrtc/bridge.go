@@ -0,0 +1,216 @@
+// Package webrtc bridges an RTSP camera/stream into the browser over
+// WebRTC, forwarding RTP packets straight into the peer connection without
+// re-encoding whenever the source codec is already WebRTC-compatible.
+package webrtc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// offerRequest/answerResponse are the bodies exchanged with /webrtc/offer.
+type offerRequest struct {
+	SDP string `json:"sdp"`
+}
+
+type answerResponse struct {
+	SessionID string `json:"sessionId"`
+	SDP       string `json:"sdp"`
+}
+
+// candidateRequest is the body posted to /webrtc/candidate for trickle ICE.
+type candidateRequest struct {
+	SessionID string                  `json:"sessionId"`
+	Candidate webrtc.ICECandidateInit `json:"candidate"`
+}
+
+// Bridge pulls a single RTSP source and fans it out to any number of
+// browser peers connected over WebRTC. One Bridge serves one RTSP source,
+// mirroring how the rest of the tool serves one video file per process.
+type Bridge struct {
+	rtspURL     string
+	stunServers []string
+
+	rtspClient *gortsplib.Client
+
+	mu       sync.Mutex
+	sessions map[string]*webrtc.PeerConnection
+
+	videoTrack *webrtc.TrackLocalStaticRTP
+	audioTrack *webrtc.TrackLocalStaticRTP
+}
+
+// New creates a Bridge for the given rtsp:// URL. stunServers configures
+// ICE so the offer/answer exchange below also works across NATs.
+func New(rtspURL string, stunServers []string) *Bridge {
+	return &Bridge{
+		rtspURL:     rtspURL,
+		stunServers: stunServers,
+		sessions:    make(map[string]*webrtc.PeerConnection),
+	}
+}
+
+// Start connects to the RTSP source, figures out which local WebRTC tracks
+// are needed for its codecs, and begins forwarding RTP packets into them.
+// It blocks until the RTSP connection fails or the bridge is stopped.
+func (b *Bridge) Start() error {
+	u, err := base.ParseURL(b.rtspURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse RTSP URL %s: %w", b.rtspURL, err)
+	}
+
+	client := &gortsplib.Client{}
+	if err := client.Start(u.Scheme, u.Host); err != nil {
+		return fmt.Errorf("failed to start RTSP client: %w", err)
+	}
+	b.rtspClient = client
+
+	desc, _, err := client.Describe(u)
+	if err != nil {
+		return fmt.Errorf("failed to describe RTSP source %s: %w", b.rtspURL, err)
+	}
+
+	if err := b.setupTracksFromDescription(desc); err != nil {
+		return err
+	}
+
+	if err := client.SetupAll(desc.BaseURL, desc.Medias); err != nil {
+		return fmt.Errorf("failed to setup RTSP media: %w", err)
+	}
+
+	client.OnPacketRTPAny(func(medi *description.Media, forma format.Format, pkt *rtp.Packet) {
+		b.forwardRTP(medi, pkt)
+	})
+
+	if _, err := client.Play(nil); err != nil {
+		return fmt.Errorf("failed to start RTSP playback: %w", err)
+	}
+
+	return client.Wait()
+}
+
+// Stop tears down the RTSP connection and every active peer connection.
+func (b *Bridge) Stop() {
+	if b.rtspClient != nil {
+		b.rtspClient.Close()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, pc := range b.sessions {
+		pc.Close()
+		delete(b.sessions, id)
+	}
+}
+
+// setupTracksFromDescription picks, for the video and audio media in desc,
+// the WebRTC codec that lets us forward RTP as-is (H264/H265 video,
+// G711 audio) rather than transcoding; AAC and other audio is skipped.
+func (b *Bridge) setupTracksFromDescription(desc *description.Session) error {
+	for _, media := range desc.Medias {
+		for _, forma := range media.Formats {
+			codecCaps, ok := webrtcCodecFor(forma)
+			if !ok {
+				continue
+			}
+
+			track, err := webrtc.NewTrackLocalStaticRTP(codecCaps, string(media.Type), "vphs-rtsp")
+			if err != nil {
+				return fmt.Errorf("failed to create %s track: %w", media.Type, err)
+			}
+
+			switch media.Type {
+			case description.MediaTypeVideo:
+				b.videoTrack = track
+			case description.MediaTypeAudio:
+				b.audioTrack = track
+			}
+			break
+		}
+	}
+
+	if b.videoTrack == nil {
+		return fmt.Errorf("RTSP source has no WebRTC-compatible video track (need H264/H265)")
+	}
+	return nil
+}
+
+// forwardRTP writes an incoming RTSP RTP packet straight to the matching
+// local track; no decode/encode happens on this path.
+func (b *Bridge) forwardRTP(medi *description.Media, pkt *rtp.Packet) {
+	raw, err := pkt.Marshal()
+	if err != nil {
+		return
+	}
+
+	var track *webrtc.TrackLocalStaticRTP
+	switch medi.Type {
+	case description.MediaTypeVideo:
+		track = b.videoTrack
+	case description.MediaTypeAudio:
+		track = b.audioTrack
+	}
+	if track == nil {
+		return
+	}
+
+	if _, err := track.Write(raw); err != nil {
+		log.Printf("webrtc: failed to forward RTP packet: %v", err)
+	}
+}
+
+// newPeerConnection builds a PeerConnection configured with the bridge's
+// STUN servers and the forwarded video/audio tracks already attached.
+func (b *Bridge) newPeerConnection() (*webrtc.PeerConnection, error) {
+	var iceServers []webrtc.ICEServer
+	for _, s := range b.stunServers {
+		iceServers = append(iceServers, webrtc.ICEServer{URLs: []string{s}})
+	}
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{ICEServers: iceServers})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create peer connection: %w", err)
+	}
+
+	if b.videoTrack != nil {
+		if _, err := pc.AddTrack(b.videoTrack); err != nil {
+			pc.Close()
+			return nil, fmt.Errorf("failed to add video track: %w", err)
+		}
+	}
+	if b.audioTrack != nil {
+		if _, err := pc.AddTrack(b.audioTrack); err != nil {
+			pc.Close()
+			return nil, fmt.Errorf("failed to add audio track: %w", err)
+		}
+	}
+
+	return pc, nil
+}
+
+// newSessionID generates a short random identifier for a peer connection so
+// later /webrtc/candidate calls can be routed to it.
+func newSessionID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// RegisterHandlers wires the offer/answer and trickle ICE endpoints onto mux.
+func (b *Bridge) RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/webrtc/offer", b.handleOffer)
+	mux.HandleFunc("/webrtc/candidate", b.handleCandidate)
+}
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/webrtc/v3"
+)
+
+// webrtcCodecFor maps an RTSP format to the WebRTC codec capability needed
+// to forward its RTP packets unchanged. Formats with no browser-compatible
+// equivalent (e.g. MPEG-4 video, most non-G711 audio codecs) are rejected
+// so the caller can skip that media instead of forwarding packets no
+// browser could decode.
+func webrtcCodecFor(f format.Format) (webrtc.RTPCodecCapability, bool) {
+	switch c := f.(type) {
+	case *format.H264:
+		return webrtc.RTPCodecCapability{
+			MimeType:    webrtc.MimeTypeH264,
+			ClockRate:   90000,
+			SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+		}, true
+	case *format.H265:
+		return webrtc.RTPCodecCapability{
+			MimeType:  "video/H265",
+			ClockRate: 90000,
+		}, true
+	case *format.G711:
+		mime := webrtc.MimeTypePCMA
+		if c.MULaw {
+			mime = webrtc.MimeTypePCMU
+		}
+		return webrtc.RTPCodecCapability{MimeType: mime, ClockRate: 8000, Channels: 1}, true
+	case *format.MPEG4Audio:
+		// Browsers' WebRTC stacks don't accept AAC as a negotiable codec, so
+		// unlike H264/H265/G711 there's no RTP-passthrough path for it - it
+		// would need a live AAC->Opus transcode, which this bridge doesn't
+		// do. Skip the track rather than forwarding packets no peer can
+		// decode; callers should prefer a source with G711 audio for now.
+		return webrtc.RTPCodecCapability{}, false
+	default:
+		return webrtc.RTPCodecCapability{}, false
+	}
+}
@@ -0,0 +1,108 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// handleOffer implements the SDP offer/answer half of the exchange: a
+// browser POSTs its offer, we create a PeerConnection with the forwarded
+// RTSP tracks attached, and return our answer plus a session id that later
+// /webrtc/candidate calls use to trickle ICE into the same PeerConnection.
+func (b *Bridge) handleOffer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req offerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid offer: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pc, err := b.newPeerConnection()
+	if err != nil {
+		http.Error(w, "failed to create peer connection: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	offer := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: req.SDP}
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		pc.Close()
+		http.Error(w, "failed to set remote description: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		pc.Close()
+		http.Error(w, "failed to create answer: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// We don't trickle our own candidates to the browser, so wait for ICE
+	// gathering to finish before answering: the promise must be created
+	// before SetLocalDescription, since that's what kicks gathering off.
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		pc.Close()
+		http.Error(w, "failed to set local description: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	<-gatherComplete
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		pc.Close()
+		http.Error(w, "failed to allocate session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b.mu.Lock()
+	b.sessions[sessionID] = pc
+	b.mu.Unlock()
+
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			b.mu.Lock()
+			delete(b.sessions, sessionID)
+			b.mu.Unlock()
+		}
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(answerResponse{SessionID: sessionID, SDP: pc.LocalDescription().SDP})
+}
+
+// handleCandidate implements the client side of trickle ICE: the browser
+// posts each ICE candidate as it's discovered and we feed it into the
+// matching PeerConnection.
+func (b *Bridge) handleCandidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req candidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid candidate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b.mu.Lock()
+	pc, ok := b.sessions[req.SessionID]
+	b.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	if err := pc.AddICECandidate(req.Candidate); err != nil {
+		http.Error(w, "failed to add ICE candidate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}